@@ -2,13 +2,31 @@ package device
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 )
 
+// macvlanModes are the kernel macvlan modes accepted by the "mode" config key, plus the "l3"
+// pseudo-mode which is implemented on top of the kernel's "private" mode by managing routes and
+// proxy ARP/NDP entries ourselves, mirroring the L3 semantics of the ipvlan driver.
+var macvlanModes = []string{"bridge", "private", "vepa", "passthru", "l3"}
+
+// Cross-device reuse gap: nic_physical.go, nic_ipvlan.go, nic_routed.go and nic_sriov.go don't exist in
+// this tree, so the gvrp, parent-state save/restore, l3 routed-NIC and MAAS registration helpers below
+// are only wired up for nicMACVLAN, even though the requests that introduced them asked for the same
+// option to be consistent across NIC types. That's a real scope cut, not just an environment quirk -
+// raise it with whoever owns those files rather than re-deriving the same helpers independently there.
+
 type nicMACVLAN struct {
 	deviceCommon
 }
@@ -25,6 +43,11 @@ func (d *nicMACVLAN) validateConfig() error {
 		"mtu",
 		"hwaddr",
 		"vlan",
+		"gvrp",
+		"mode",
+		"ipv4.address",
+		"ipv6.address",
+		"vhost",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
 		"boot.priority",
@@ -34,6 +57,31 @@ func (d *nicMACVLAN) validateConfig() error {
 		return err
 	}
 
+	if d.config["mode"] != "" && !shared.StringInSlice(d.config["mode"], macvlanModes) {
+		return fmt.Errorf("Invalid mode %q, must be one of: %s", d.config["mode"], strings.Join(macvlanModes, ", "))
+	}
+
+	if d.config["mode"] == "l3" && d.config["ipv4.address"] == "" && d.config["ipv6.address"] == "" {
+		return fmt.Errorf("mode=l3 requires at least one of ipv4.address or ipv6.address to be set")
+	}
+
+	if d.config["mode"] != "l3" && (d.config["ipv4.address"] != "" || d.config["ipv6.address"] != "") {
+		return fmt.Errorf("ipv4.address and ipv6.address are only valid when mode is l3")
+	}
+
+	// mode=l3 adds a host route pointing at host_name, the macvtap/macvlan child device. For containers
+	// that device is moved wholesale into the container's network namespace by the generic instance
+	// start code right after Start() returns (see the "link" RunConfig.NetworkInterface entry), so the
+	// host-side route would point at a device that no longer exists there. For VMs the macvtap char
+	// device stays in the host netns (qemu reads/writes it from there), so only VMs can use mode=l3.
+	if d.config["mode"] == "l3" && d.inst.Type() != instancetype.VM {
+		return fmt.Errorf("mode=l3 is only valid for virtual machines")
+	}
+
+	if shared.IsTrue(d.config["vhost"]) && d.inst.Type() != instancetype.VM {
+		return fmt.Errorf("vhost is only valid for virtual machines")
+	}
+
 	return nil
 }
 
@@ -72,8 +120,10 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 	// Record the temporary device name used for deletion later.
 	saveData["host_name"] = NetworkRandomDevName("mac")
 
-	// Create VLAN parent device if needed.
-	statusDev, err := NetworkCreateVlanDeviceIfNeeded(d.state, d.config["parent"], parentName, d.config["vlan"])
+	// Create VLAN parent device if needed. When the parent is created for this device and gvrp is set,
+	// GVRP is enabled on it so that the upstream switch can dynamically learn/propagate the VLAN via GARP VLAN
+	// Registration Protocol, rather than requiring the trunk to be configured manually.
+	statusDev, err := NetworkCreateVlanDeviceIfNeeded(d.state, d.config["parent"], parentName, d.config["vlan"], shared.IsTrue(d.config["gvrp"]))
 	if err != nil {
 		return nil, err
 	}
@@ -81,21 +131,46 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 	// Record whether we created the parent device or not so it can be removed on stop.
 	saveData["last_state.created"] = fmt.Sprintf("%t", statusDev != "existing")
 
+	// If we didn't create the parent ourselves, it may be shared with other instances, so record its
+	// current MTU, operstate and hwaddr before we touch anything, and restore them in postStop when this
+	// device stops; postStop does not check whether other instances still use the parent. This avoids the last instance to stop leaving the parent modified
+	// for whoever uses it next.
+	if !shared.IsTrue(saveData["last_state.created"]) {
+		parentMTU, parentUp, parentHwaddr, err := networkGetParentState(parentName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get current state of parent %q: %v", parentName, err)
+		}
+
+		saveData["last_state.parent.mtu"] = parentMTU
+		saveData["last_state.parent.up"] = fmt.Sprintf("%t", parentUp)
+		saveData["last_state.parent.hwaddr"] = parentHwaddr
+	}
+
 	if shared.IsTrue(saveData["last_state.created"]) {
 		revert.Add(func() {
 			NetworkRemoveInterfaceIfNeeded(d.state, parentName, d.inst, d.config["parent"], d.config["vlan"])
 		})
 	}
 
+	// The "l3" mode isn't a real kernel macvlan/macvtap mode. It is built on top of "private" (which
+	// disables L2 forwarding between child devices on the same parent) and is combined with routes and
+	// proxy ARP/NDP entries set up below so traffic is forwarded at L3 instead.
+	kernelMode := d.config["mode"]
+	if kernelMode == "" {
+		kernelMode = "bridge"
+	} else if kernelMode == "l3" {
+		kernelMode = "private"
+	}
+
 	if d.inst.Type() == instancetype.Container {
 		// Create MACVLAN interface.
-		_, err = shared.RunCommand("ip", "link", "add", "dev", saveData["host_name"], "link", parentName, "type", "macvlan", "mode", "bridge")
+		_, err = shared.RunCommand("ip", "link", "add", "dev", saveData["host_name"], "link", parentName, "type", "macvlan", "mode", kernelMode)
 		if err != nil {
 			return nil, err
 		}
 	} else if d.inst.Type() == instancetype.VM {
 		// Create MACVTAP interface.
-		_, err = shared.RunCommand("ip", "link", "add", "dev", saveData["host_name"], "link", parentName, "type", "macvtap", "mode", "bridge")
+		_, err = shared.RunCommand("ip", "link", "add", "dev", saveData["host_name"], "link", parentName, "type", "macvtap", "mode", kernelMode)
 		if err != nil {
 			return nil, err
 		}
@@ -125,8 +200,73 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 		if err != nil {
 			return nil, fmt.Errorf("Failed to bring up interface %s: %v", saveData["host_name"], err)
 		}
+
+		// With vhost=true, open the macvtap character device and /dev/vhost-net ourselves and hand
+		// the fds to qemu via RunConfig, rather than letting qemu open the tap by name. This gives
+		// vhost-net-backed macvtap throughput without requiring a bridged or SR-IOV setup. The fd
+		// numbers are round-tripped through volatile state, the same as every other piece of state
+		// this device needs between Start and Stop, rather than kept alive on the device struct
+		// (which doesn't persist between the two calls).
+		if shared.IsTrue(d.config["vhost"]) {
+			tapFile, err := networkOpenMACVTAP(saveData["host_name"])
+			if err != nil {
+				return nil, err
+			}
+
+			// Keep raw fds rather than *os.File values, whose finalizers would close the fds once
+			// they are garbage collected after Start returns.
+			tapFD, err := syscall.Dup(int(tapFile.Fd()))
+			tapFile.Close()
+			if err != nil {
+				return nil, fmt.Errorf("Failed to duplicate tap fd: %v", err)
+			}
+			saveData["tap_fd"] = fmt.Sprintf("%d", tapFD)
+			revert.Add(func() { syscall.Close(tapFD) })
+
+			vhostFD, err := syscall.Open("/dev/vhost-net", syscall.O_RDWR, 0)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to open /dev/vhost-net: %v", err)
+			}
+			saveData["vhost_fd"] = fmt.Sprintf("%d", vhostFD)
+			revert.Add(func() { syscall.Close(vhostFD) })
+		}
+	}
+
+	// In l3 mode there is no L2 learning between the host and the instance, so routes pointing at the
+	// child device and proxy ARP/NDP entries on the parent are used instead, the same way nic_routed
+	// provides routed NIC semantics. Only valid for VMs (enforced in validateConfig) since the macvtap
+	// device stays in the host netns, unlike the macvlan device used for containers.
+	if d.config["mode"] == "l3" {
+		routeAddresses := networkNICRoutedAddresses(d.config)
+
+		err = networkNICRouteAdd(saveData["host_name"], routeAddresses...)
+		if err != nil {
+			return nil, err
+		}
+		revert.Add(func() { networkNICRouteDelete(saveData["host_name"], routeAddresses...) })
+
+		err = networkNICProxyAdd(parentName, routeAddresses...)
+		if err != nil {
+			return nil, err
+		}
+		revert.Add(func() { networkNICProxyDelete(parentName, routeAddresses...) })
+	}
+
+	// Register the instance's hwaddr with the configured MAAS subnet(s), the same way nic_bridged does
+	// for bridged NICs. d.config["hwaddr"] is only set when the user picked an explicit MAC, so read
+	// back the live MAC of the device we just created instead, which covers the common case of the
+	// kernel auto-assigning one.
+	_, _, liveHwaddr, err := networkGetParentState(saveData["host_name"])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get MAC address of %q: %v", saveData["host_name"], err)
 	}
 
+	err = networkMAASInterface(d.state, d.inst, d.config, liveHwaddr)
+	if err != nil {
+		return nil, err
+	}
+	revert.Add(func() { networkMAASDeregister(d.state, d.inst, d.config) })
+
 	err = d.volatileSet(saveData)
 	if err != nil {
 		return nil, err
@@ -145,6 +285,18 @@ func (d *nicMACVLAN) Start() (*deviceConfig.RunConfig, error) {
 		runConf.NetworkInterface = append(runConf.NetworkInterface,
 			deviceConfig.RunConfigItem{Key: "hwaddr", Value: d.config["hwaddr"]},
 		)
+
+		if saveData["tap_fd"] != "" {
+			runConf.NetworkInterface = append(runConf.NetworkInterface,
+				deviceConfig.RunConfigItem{Key: "tapFD", Value: saveData["tap_fd"]},
+			)
+		}
+
+		if saveData["vhost_fd"] != "" {
+			runConf.NetworkInterface = append(runConf.NetworkInterface,
+				deviceConfig.RunConfigItem{Key: "vhostFD", Value: saveData["vhost_fd"]},
+			)
+		}
 	}
 
 	revert.Success()
@@ -161,21 +313,56 @@ func (d *nicMACVLAN) Stop() (*deviceConfig.RunConfig, error) {
 		},
 	}
 
+	// Close the tap/vhost-net fds opened in Start for a vhost=true VM device, now that qemu no longer
+	// needs them. The fd numbers were recorded in volatile state rather than kept on the device struct,
+	// since the object running Stop isn't guaranteed to be the one that ran Start.
+	err := networkCloseFD(v["tap_fd"])
+	if err != nil {
+		return nil, err
+	}
+
+	err = networkCloseFD(v["vhost_fd"])
+	if err != nil {
+		return nil, err
+	}
+
 	return &runConf, nil
 }
 
 // postStop is run after the device is removed from the instance.
 func (d *nicMACVLAN) postStop() error {
 	defer d.volatileSet(map[string]string{
-		"host_name":          "",
-		"last_state.hwaddr":  "",
-		"last_state.mtu":     "",
-		"last_state.created": "",
+		"host_name":                "",
+		"tap_fd":                   "",
+		"vhost_fd":                 "",
+		"last_state.hwaddr":        "",
+		"last_state.mtu":           "",
+		"last_state.created":       "",
+		"last_state.parent.mtu":    "",
+		"last_state.parent.up":     "",
+		"last_state.parent.hwaddr": "",
 	})
 
 	errs := []error{}
 	v := d.volatileGet()
 
+	// Remove the l3 mode routes and proxy ARP/NDP entries, if any. This must happen before the child
+	// device is deleted below, as networkNICRouteDelete needs it to still exist.
+	if d.config["mode"] == "l3" {
+		parentName := NetworkGetHostDevice(d.config["parent"], d.config["vlan"])
+		routeAddresses := networkNICRoutedAddresses(d.config)
+
+		err := networkNICRouteDelete(v["host_name"], routeAddresses...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		err = networkNICProxyDelete(parentName, routeAddresses...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	// Delete the detached device.
 	if v["host_name"] != "" && shared.PathExists(fmt.Sprintf("/sys/class/net/%s", v["host_name"])) {
 		err := NetworkRemoveInterface(v["host_name"])
@@ -184,14 +371,31 @@ func (d *nicMACVLAN) postStop() error {
 		}
 	}
 
-	// This will delete the parent interface if we created it for VLAN parent.
+	// Deregister the instance from the configured MAAS subnet(s).
+	err := networkMAASDeregister(d.state, d.inst, d.config)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// This will delete the parent interface if we created it for VLAN parent, otherwise restore its
+	// pre-existing MTU, operstate and hwaddr, in case we (or another shared instance) changed them.
+	// Locked against Start (and against other instances' postStop) for the same reason Start locks
+	// around creating/capturing the parent: several instances can share this parent concurrently.
+	networkCreateSharedDeviceLock.Lock()
 	if shared.IsTrue(v["last_state.created"]) {
 		parentName := NetworkGetHostDevice(d.config["parent"], d.config["vlan"])
 		err := NetworkRemoveInterfaceIfNeeded(d.state, parentName, d.inst, d.config["parent"], d.config["vlan"])
 		if err != nil {
 			errs = append(errs, err)
 		}
+	} else {
+		parentName := NetworkGetHostDevice(d.config["parent"], d.config["vlan"])
+		err := networkRestoreParentState(parentName, v["last_state.parent.mtu"], v["last_state.parent.up"], v["last_state.parent.hwaddr"])
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
+	networkCreateSharedDeviceLock.Unlock()
 
 	if len(errs) > 0 {
 		return fmt.Errorf("%v", errs)
@@ -199,3 +403,251 @@ func (d *nicMACVLAN) postStop() error {
 
 	return nil
 }
+
+// networkMAASInterface registers hwaddr with the maas.subnet.ipv4/maas.subnet.ipv6 subnets configured
+// on config, if any. Intended to be the shared code path for nic_bridged/nic_physical/nic_ipvlan/
+// nic_sriov too - see the cross-device reuse gap noted near the top of this file.
+func networkMAASInterface(s *state.State, inst instance.Instance, config map[string]string, hwaddr string) error {
+	if config["maas.subnet.ipv4"] == "" && config["maas.subnet.ipv6"] == "" {
+		return nil
+	}
+
+	if s.MAAS == nil {
+		return fmt.Errorf("Can't perform MAAS configuration as server isn't configured with MAAS")
+	}
+
+	subnets := []string{}
+	if config["maas.subnet.ipv4"] != "" {
+		subnets = append(subnets, config["maas.subnet.ipv4"])
+	}
+
+	if config["maas.subnet.ipv6"] != "" {
+		subnets = append(subnets, config["maas.subnet.ipv6"])
+	}
+
+	return s.MAAS.CreateContainer(inst.Name(), hwaddr, subnets)
+}
+
+// networkMAASDeregister is the inverse of networkMAASInterface.
+func networkMAASDeregister(s *state.State, inst instance.Instance, config map[string]string) error {
+	if config["maas.subnet.ipv4"] == "" && config["maas.subnet.ipv6"] == "" {
+		return nil
+	}
+
+	if s.MAAS == nil {
+		return nil
+	}
+
+	return s.MAAS.DeleteContainer(inst.Name())
+}
+
+// networkOpenMACVTAP opens the /dev/tapN character device for the macvtap interface named devName, for
+// handing its fd directly to qemu instead of letting qemu open the tap by name.
+func networkOpenMACVTAP(devName string) (*os.File, error) {
+	iface, err := net.InterfaceByName(devName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get interface %q: %v", devName, err)
+	}
+
+	tapPath := fmt.Sprintf("/dev/tap%d", iface.Index)
+	f, err := os.OpenFile(tapPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %q: %v", tapPath, err)
+	}
+
+	return f, nil
+}
+
+// networkCloseFD closes the file descriptor number recorded in fdStr (e.g. the tap_fd/vhost_fd volatile
+// keys), used to deterministically close fds opened in Start once Stop no longer needs them, without
+// keeping the *os.File itself alive on the device struct across the Start/Stop boundary.
+func networkCloseFD(fdStr string) error {
+	if fdStr == "" {
+		return nil
+	}
+
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		return fmt.Errorf("Invalid file descriptor %q: %v", fdStr, err)
+	}
+
+	return os.NewFile(uintptr(fd), "").Close()
+}
+
+// networkGetParentState reads devName's current MTU, up/down state and hwaddr from sysfs. Used both to
+// read back the live MAC of a just-created device (e.g. for MAAS registration when hwaddr wasn't set
+// explicitly) and, for a pre-existing parent, to save state that can be restored afterwards if this
+// device has to temporarily modify it. Intended to be shared with nic_physical/nic_ipvlan too - see the
+// cross-device reuse gap noted near the top of this file.
+func networkGetParentState(devName string) (mtu string, up bool, hwaddr string, err error) {
+	mtuBytes, err := os.ReadFile(fmt.Sprintf("/sys/class/net/%s/mtu", devName))
+	if err != nil {
+		return "", false, "", err
+	}
+
+	operstateBytes, err := os.ReadFile(fmt.Sprintf("/sys/class/net/%s/operstate", devName))
+	if err != nil {
+		return "", false, "", err
+	}
+
+	hwaddrBytes, err := os.ReadFile(fmt.Sprintf("/sys/class/net/%s/address", devName))
+	if err != nil {
+		return "", false, "", err
+	}
+
+	return strings.TrimSpace(string(mtuBytes)), strings.TrimSpace(string(operstateBytes)) == "up", strings.TrimSpace(string(hwaddrBytes)), nil
+}
+
+// networkRestoreParentState restores devName's MTU, up/down state and hwaddr as previously captured by
+// networkGetParentState. It is a no-op for any value that wasn't recorded, and for any value that
+// already matches devName's live state, so instances that never actually touch the parent (as is
+// currently the case for nicMACVLAN) don't churn a shared parent's MAC/MTU/state on every stop.
+func networkRestoreParentState(devName string, mtu string, up string, hwaddr string) error {
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", devName)) {
+		return nil
+	}
+
+	liveMTU, liveUp, liveHwaddr, err := networkGetParentState(devName)
+	if err != nil {
+		return fmt.Errorf("Failed to get current state of %q: %v", devName, err)
+	}
+
+	if hwaddr != "" && hwaddr != liveHwaddr {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", devName, "address", hwaddr)
+		if err != nil {
+			return fmt.Errorf("Failed to restore MAC address on %q: %v", devName, err)
+		}
+	}
+
+	if mtu != "" && mtu != liveMTU {
+		_, err := shared.RunCommand("ip", "link", "set", "dev", devName, "mtu", mtu)
+		if err != nil {
+			return fmt.Errorf("Failed to restore MTU on %q: %v", devName, err)
+		}
+	}
+
+	if up != "" && shared.IsTrue(up) != liveUp {
+		state := "down"
+		if shared.IsTrue(up) {
+			state = "up"
+		}
+
+		_, err := shared.RunCommand("ip", "link", "set", "dev", devName, state)
+		if err != nil {
+			return fmt.Errorf("Failed to restore %s state on %q: %v", state, devName, err)
+		}
+	}
+
+	return nil
+}
+
+// networkNICRoutedAddresses returns the list of addresses configured via ipv4.address/ipv6.address,
+// for nicMACVLAN's l3 mode (see the cross-device reuse gap noted near the top of this file).
+func networkNICRoutedAddresses(config deviceConfig.Device) []string {
+	addresses := []string{}
+
+	if config["ipv4.address"] != "" {
+		for _, addr := range strings.Split(config["ipv4.address"], ",") {
+			addresses = append(addresses, strings.TrimSpace(addr))
+		}
+	}
+
+	if config["ipv6.address"] != "" {
+		for _, addr := range strings.Split(config["ipv6.address"], ",") {
+			addresses = append(addresses, strings.TrimSpace(addr))
+		}
+	}
+
+	return addresses
+}
+
+// networkNICRouteAdd adds host routes for the supplied addresses pointing at routeDev, used to
+// implement routed (L3) NIC semantics without relying on L2 bridging/learning.
+func networkNICRouteAdd(routeDev string, addresses ...string) error {
+	for _, addr := range addresses {
+		family := "-4"
+		if strings.Contains(addr, ":") {
+			family = "-6"
+		}
+
+		_, err := shared.RunCommand("ip", family, "route", "add", networkNICRouteCIDR(addr), "dev", routeDev)
+		if err != nil {
+			return fmt.Errorf("Failed to add route %q: %v", addr, err)
+		}
+	}
+
+	return nil
+}
+
+// networkNICRouteDelete is the inverse of networkNICRouteAdd.
+func networkNICRouteDelete(routeDev string, addresses ...string) error {
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", routeDev)) {
+		return nil
+	}
+
+	for _, addr := range addresses {
+		family := "-4"
+		if strings.Contains(addr, ":") {
+			family = "-6"
+		}
+
+		_, err := shared.RunCommand("ip", family, "route", "delete", networkNICRouteCIDR(addr), "dev", routeDev)
+		if err != nil {
+			return fmt.Errorf("Failed to remove route %q: %v", addr, err)
+		}
+	}
+
+	return nil
+}
+
+// networkNICRouteCIDR returns addr as a host route (/32 for IPv4, /128 for IPv6).
+func networkNICRouteCIDR(addr string) string {
+	if strings.Contains(addr, ":") {
+		return fmt.Sprintf("%s/128", addr)
+	}
+
+	return fmt.Sprintf("%s/32", addr)
+}
+
+// networkNICProxyAdd adds proxy ARP (IPv4) / proxy NDP (IPv6) entries on parentName for the supplied
+// addresses, so the upstream network resolves them to the parent while the l3-mode child device
+// actually routes the traffic on.
+func networkNICProxyAdd(parentName string, addresses ...string) error {
+	for _, addr := range addresses {
+		if strings.Contains(addr, ":") {
+			_, err := shared.RunCommand("ip", "-6", "neigh", "add", "proxy", addr, "dev", parentName)
+			if err != nil {
+				return fmt.Errorf("Failed to add proxy NDP entry for %q: %v", addr, err)
+			}
+		} else {
+			_, err := shared.RunCommand("ip", "-4", "neigh", "add", "proxy", addr, "dev", parentName)
+			if err != nil {
+				return fmt.Errorf("Failed to add proxy ARP entry for %q: %v", addr, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// networkNICProxyDelete is the inverse of networkNICProxyAdd.
+func networkNICProxyDelete(parentName string, addresses ...string) error {
+	if !shared.PathExists(fmt.Sprintf("/sys/class/net/%s", parentName)) {
+		return nil
+	}
+
+	for _, addr := range addresses {
+		var err error
+		if strings.Contains(addr, ":") {
+			_, err = shared.RunCommand("ip", "-6", "neigh", "del", "proxy", addr, "dev", parentName)
+		} else {
+			_, err = shared.RunCommand("ip", "-4", "neigh", "del", "proxy", addr, "dev", parentName)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to remove proxy NDP/ARP entry for %q: %v", addr, err)
+		}
+	}
+
+	return nil
+}